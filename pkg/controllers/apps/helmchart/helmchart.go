@@ -18,12 +18,21 @@ package helmchart
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
@@ -37,7 +46,48 @@ import (
 	appListers "github.com/clusternet/clusternet/pkg/generated/listers/apps/v1alpha1"
 )
 
-type SyncHandlerFunc func(chart *appsapi.HelmChart) error
+const (
+	// FailurePolicyAbort stops retrying a HelmChart once BackOffLimit is exceeded.
+	FailurePolicyAbort = "abort"
+	// FailurePolicyReinstall triggers an uninstall+reinstall instead of a plain upgrade retry
+	// once the chart is failing.
+	FailurePolicyReinstall = "reinstall"
+
+	// defaultTimeout bounds a single sync attempt when HelmChart.Spec.Timeout is unset.
+	defaultTimeout = 5 * time.Minute
+
+	// defaultStatusResyncPeriod is how often the status updater loop polls the actual release
+	// state of every HelmChart, independent of spec changes seen by the sync workqueue.
+	defaultStatusResyncPeriod = 30 * time.Second
+
+	// ConditionReady reports whether the release is reconciled and usable.
+	ConditionReady = "Ready"
+	// ConditionReleased reports whether the Helm release install/upgrade succeeded.
+	ConditionReleased = "Released"
+	// ConditionChartPulled reports whether the chart artifact was fetched successfully.
+	ConditionChartPulled = "ChartPulled"
+	// ConditionTestSucceeded reports the outcome of the release's Helm tests, if any.
+	ConditionTestSucceeded = "TestSucceeded"
+
+	// ociChartPrefix marks a HelmChart as sourced from an OCI registry rather than a
+	// traditional Helm repository.
+	ociChartPrefix = "oci://"
+
+	// chartWorkDir is the base directory charts are materialized into before being handed to
+	// SyncHandler, keyed per-chart by namespace/name.
+	chartWorkDir = "/tmp/clusternet/helmcharts"
+)
+
+type SyncHandlerFunc func(ctx context.Context, chart *appsapi.HelmChart) error
+
+// StatusHandlerFunc polls the actual Helm release backing chart and returns the status that
+// should be merged onto the HelmChart, independent of the spec-driven sync workqueue.
+type StatusHandlerFunc func(ctx context.Context, chart *appsapi.HelmChart) (*appsapi.HelmChartStatus, error)
+
+// OCIPullHandlerFunc pulls an `oci://` chart referenced by chart into destDir, authenticating
+// with chart.Spec.AuthSecret when set, and returns the resolved digest/revision of the pulled
+// artifact.
+type OCIPullHandlerFunc func(ctx context.Context, chart *appsapi.HelmChart, destDir string) (revision string, err error)
 
 // Controller is a controller that handle HelmChart
 type Controller struct {
@@ -56,21 +106,44 @@ type Controller struct {
 	helmChartSynced cache.InformerSynced
 
 	SyncHandler SyncHandlerFunc
+
+	// UninstallHandler uninstalls the release backing a HelmChart. It is invoked before a
+	// reinstall when the chart's FailurePolicy is "reinstall". Optional; a nil handler makes
+	// FailurePolicy=reinstall behave like a plain retry.
+	UninstallHandler SyncHandlerFunc
+
+	// StatusHandler polls the actual release state for a HelmChart. It is run by the status
+	// updater loop, not the sync workqueue, so drift that isn't triggered by a spec change
+	// (rollback, manual uninstall, failed hooks) is still surfaced. Optional; a nil handler
+	// disables the status updater loop.
+	StatusHandler StatusHandlerFunc
+
+	// OCIPullHandler resolves `oci://` chart sources. Optional; if nil, HelmCharts referencing
+	// an OCI source fail sync with a clear error instead of being silently ignored.
+	OCIPullHandler OCIPullHandlerFunc
+
+	// statusResyncPeriod controls how often the status updater loop polls every HelmChart.
+	statusResyncPeriod time.Duration
 }
 
 func NewController(ctx context.Context, clusternetClient clusternetClientSet.Interface,
-	helmChartInformer appInformers.HelmChartInformer, syncHandler SyncHandlerFunc) (*Controller, error) {
+	helmChartInformer appInformers.HelmChartInformer, syncHandler, uninstallHandler SyncHandlerFunc,
+	statusHandler StatusHandlerFunc, ociPullHandler OCIPullHandlerFunc) (*Controller, error) {
 	if syncHandler == nil {
 		return nil, fmt.Errorf("syncHandler must be set")
 	}
 
 	c := &Controller{
-		ctx:              ctx,
-		clusternetClient: clusternetClient,
-		workqueue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "helmChart"),
-		helmChartLister:  helmChartInformer.Lister(),
-		helmChartSynced:  helmChartInformer.Informer().HasSynced,
-		SyncHandler:      syncHandler,
+		ctx:                ctx,
+		clusternetClient:   clusternetClient,
+		workqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "helmChart"),
+		helmChartLister:    helmChartInformer.Lister(),
+		helmChartSynced:    helmChartInformer.Informer().HasSynced,
+		SyncHandler:        syncHandler,
+		UninstallHandler:   uninstallHandler,
+		StatusHandler:      statusHandler,
+		OCIPullHandler:     ociPullHandler,
+		statusResyncPeriod: defaultStatusResyncPeriod,
 	}
 
 	// Manage the addition/update of HelmChart
@@ -87,7 +160,11 @@ func NewController(ctx context.Context, clusternetClient clusternetClientSet.Int
 // as syncing informer caches and starting workers. It will block until stopCh
 // is closed, at which point it will shutdown the workqueue and wait for
 // workers to finish processing their current work items.
-func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+//
+// statusWorkers controls the size of the separate status updater loop, which polls the actual
+// Helm release state for every HelmChart on its own schedule, decoupled from the sync
+// workqueue. Passing 0 disables the status updater loop.
+func (c *Controller) Run(workers, statusWorkers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
 
@@ -106,6 +183,14 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
 
+	if c.StatusHandler != nil && statusWorkers > 0 {
+		klog.V(5).Infof("starting %d status updater threads", statusWorkers)
+		for i := 0; i < statusWorkers; i++ {
+			shard := i
+			go wait.Until(func() { c.runStatusWorker(shard, statusWorkers) }, c.statusResyncPeriod, stopCh)
+		}
+	}
+
 	<-stopCh
 }
 
@@ -227,7 +312,7 @@ func (c *Controller) syncHandler(key string) error {
 
 	klog.V(4).Infof("start processing HelmChart %q", key)
 	// Get the HelmChart resource with this name
-	chart, err := c.helmChartLister.HelmCharts(ns).Get(name)
+	cachedChart, err := c.helmChartLister.HelmCharts(ns).Get(name)
 	// The HelmChart resource may no longer exist, in which case we stop processing.
 	if errors.IsNotFound(err) {
 		klog.V(2).Infof("HelmChart %q has been deleted", key)
@@ -236,10 +321,202 @@ func (c *Controller) syncHandler(key string) error {
 	if err != nil {
 		return err
 	}
+	// cachedChart is a pointer into the shared informer cache; never mutate it in place.
+	chart := cachedChart.DeepCopy()
+
+	if chart.Spec.BackOffLimit != nil && chart.Status.Attempts >= *chart.Spec.BackOffLimit {
+		klog.Warningf("HelmChart %q has exceeded its BackOffLimit of %d, refusing to requeue", key, *chart.Spec.BackOffLimit)
+		return c.UpdateChartStatus(chart, &appsapi.HelmChartStatus{
+			Phase:    appsapi.HelmChartPhaseFailure,
+			Reason:   "BackOffLimitExceeded",
+			Attempts: chart.Status.Attempts,
+			Conditions: []appsapi.HelmChartCondition{
+				{
+					Type:               ConditionReady,
+					Status:             corev1.ConditionFalse,
+					Reason:             "BackOffLimitExceeded",
+					Message:            fmt.Sprintf("exceeded BackOffLimit of %d attempts", *chart.Spec.BackOffLimit),
+					LastTransitionTime: metav1.Now(),
+				},
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, timeoutOrDefault(chart.Spec.Timeout))
+	defer cancel()
+
+	revision, err := c.resolveChartSource(ctx, chart)
+	if err != nil {
+		return err
+	}
+	if chartSourceUnchanged(revision, chart.Status) {
+		klog.V(4).Infof("HelmChart %q source is unchanged at already-applied revision %q, skipping sync", key, revision)
+		return nil
+	}
+
+	err = c.SyncHandler(ctx, chart)
+	if revision != "" {
+		chart.Status.LastAttemptedRevision = revision
+		if err == nil {
+			chart.Status.LastAppliedRevision = revision
+		}
+		if updateErr := c.UpdateChartStatus(chart, &chart.Status); updateErr != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to record resolved revision for HelmChart %q: %v", key, updateErr))
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	chart.Status.Attempts++
+	if chart.Spec.FailurePolicy == FailurePolicyReinstall {
+		klog.Infof("HelmChart %q failed with FailurePolicy=reinstall, will uninstall before retrying: %v", key, err)
+		if uninstallErr := c.uninstall(ctx, chart); uninstallErr != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to uninstall HelmChart %q before reinstall: %v", key, uninstallErr))
+		}
+	}
+
+	if updateErr := c.UpdateChartStatus(chart, &chart.Status); updateErr != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to record attempt count for HelmChart %q: %v", key, updateErr))
+	}
+	return err
+}
+
+// uninstall removes the release backing chart so a subsequent sync performs a clean install
+// instead of upgrading on top of a broken release. Used by FailurePolicy=reinstall.
+func (c *Controller) uninstall(ctx context.Context, chart *appsapi.HelmChart) error {
+	if c.UninstallHandler == nil {
+		return nil
+	}
+	return c.UninstallHandler(ctx, chart)
+}
+
+// timeoutOrDefault returns the per-item context timeout for a sync attempt, falling back to
+// defaultTimeout when the HelmChart does not specify one.
+func timeoutOrDefault(timeout *metav1.Duration) time.Duration {
+	if timeout == nil {
+		return defaultTimeout
+	}
+	return timeout.Duration
+}
+
+// resolveChartSource dispatches on chart's source type, materializing its bytes into a per-chart
+// working directory before SyncHandler is invoked. It returns the resolved digest/revision of
+// the materialized chart, or "" for charts that use the traditional repository+version source
+// SyncHandler already knows how to pull.
+func (c *Controller) resolveChartSource(ctx context.Context, chart *appsapi.HelmChart) (string, error) {
+	switch {
+	case chart.Spec.ChartContent != "":
+		return materializeInlineChart(chart)
+	case strings.HasPrefix(chart.Spec.Chart, ociChartPrefix):
+		if c.OCIPullHandler == nil {
+			return "", fmt.Errorf("HelmChart %q references an OCI source %q but no OCIPullHandler is configured", klog.KObj(chart), chart.Spec.Chart)
+		}
+		return c.OCIPullHandler(ctx, chart, chartDir(chart))
+	default:
+		return "", nil
+	}
+}
+
+// chartSourceUnchanged reports whether revision has already been successfully applied to chart,
+// so syncHandler can skip a redundant SyncHandler invocation. A failed attempt never updates
+// LastAppliedRevision, so an unchanged chart that previously failed to sync is not skipped here.
+func chartSourceUnchanged(revision string, status appsapi.HelmChartStatus) bool {
+	return revision != "" && revision == status.LastAppliedRevision
+}
+
+// materializeInlineChart decodes chart.Spec.ChartContent and writes it into the chart's working
+// directory, returning a sha256 digest of the decoded bytes as the revision.
+func materializeInlineChart(chart *appsapi.HelmChart) (string, error) {
+	content, err := base64.StdEncoding.DecodeString(chart.Spec.ChartContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ChartContent for HelmChart %q: %v", klog.KObj(chart), err)
+	}
+
+	dir := chartDir(chart)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create chart working directory %q: %v", dir, err)
+	}
+
+	digest := sha256.Sum256(content)
+	revision := hex.EncodeToString(digest[:])
+
+	if err := os.WriteFile(filepath.Join(dir, "chart.tgz"), content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write chart content for HelmChart %q: %v", klog.KObj(chart), err)
+	}
+	return revision, nil
+}
+
+// chartDir returns the per-chart working directory chart content is materialized into.
+func chartDir(chart *appsapi.HelmChart) string {
+	return filepath.Join(chartWorkDir, chart.Namespace, chart.Name)
+}
+
+// runStatusWorker makes one pass over its shard of the known HelmCharts, polling the actual
+// release state of each and merging it onto status, independent of the sync workqueue. Run
+// repeats it every statusResyncPeriod. shard/totalShards partition the full chart list across
+// the statusWorkers started by Run, so each chart is polled by exactly one worker.
+func (c *Controller) runStatusWorker(shard, totalShards int) {
+	charts, err := c.helmChartLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to list HelmCharts for status update: %v", err))
+		return
+	}
+
+	for _, chart := range charts {
+		if totalShards > 1 && int(fnv32(chart.Namespace+"/"+chart.Name))%totalShards != shard {
+			continue
+		}
 
-	return c.SyncHandler(chart)
+		status, err := c.StatusHandler(c.ctx, chart)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to poll release status for HelmChart %q: %v", klog.KObj(chart), err))
+			continue
+		}
+		if status == nil {
+			continue
+		}
+		// chart comes from the shared informer cache and UpdateChartStatus writes to it, so hand it a copy.
+		if err := c.UpdateChartStatus(chart.DeepCopy(), status); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to update status for HelmChart %q: %v", klog.KObj(chart), err))
+		}
+	}
 }
 
+// fnv32 hashes key to deterministically assign a HelmChart to one status-worker shard.
+func fnv32(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// mergeConditions returns existing with each condition in updates applied: conditions sharing a
+// Type are replaced in place, new Types are appended. Callers own the returned slice.
+func mergeConditions(existing, updates []appsapi.HelmChartCondition) []appsapi.HelmChartCondition {
+	merged := make([]appsapi.HelmChartCondition, len(existing))
+	copy(merged, existing)
+
+	for _, update := range updates {
+		found := false
+		for i := range merged {
+			if merged[i].Type == update.Type {
+				merged[i] = update
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, update)
+		}
+	}
+	return merged
+}
+
+// UpdateChartStatus writes status onto chart and persists it. Conditions are merged by Type
+// rather than overwritten, so the sync loop and the status updater loop can each report on their
+// own subset of conditions without clobbering the other's, and an empty LastAppliedRevision or
+// LastAttemptedRevision in status keeps chart's current value. Every other field of status
+// replaces chart's current value.
 func (c *Controller) UpdateChartStatus(chart *appsapi.HelmChart, status *appsapi.HelmChartStatus) error {
 	// NEVER modify objects from the store. It's a read-only, local cache.
 	// You can use DeepCopy() to make a deep copy of original object and modify this copy
@@ -248,7 +525,16 @@ func (c *Controller) UpdateChartStatus(chart *appsapi.HelmChart, status *appsapi
 	klog.V(5).Infof("try to update HelmChart %q status", chart.Name)
 
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		chart.Status = *status
+		merged := *status
+		merged.Conditions = mergeConditions(chart.Status.Conditions, status.Conditions)
+		if status.LastAppliedRevision == "" {
+			merged.LastAppliedRevision = chart.Status.LastAppliedRevision
+		}
+		if status.LastAttemptedRevision == "" {
+			merged.LastAttemptedRevision = chart.Status.LastAttemptedRevision
+		}
+		chart.Status = merged
+
 		_, err := c.clusternetClient.AppsV1alpha1().HelmCharts(chart.Namespace).UpdateStatus(c.ctx, chart, metav1.UpdateOptions{})
 		if err == nil {
 			//TODO
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Clusternet Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmchart
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsapi "github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1"
+)
+
+func TestChartSourceUnchanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision string
+		status   appsapi.HelmChartStatus
+		want     bool
+	}{
+		{
+			name:     "no resolved revision, always sync",
+			revision: "",
+			status:   appsapi.HelmChartStatus{LastAppliedRevision: "abc"},
+			want:     false,
+		},
+		{
+			name:     "matches last applied revision, skip",
+			revision: "abc",
+			status:   appsapi.HelmChartStatus{LastAppliedRevision: "abc"},
+			want:     true,
+		},
+		{
+			name:     "previous attempt failed, must not skip even if content is unchanged",
+			revision: "abc",
+			status:   appsapi.HelmChartStatus{LastAttemptedRevision: "abc", LastAppliedRevision: ""},
+			want:     false,
+		},
+		{
+			name:     "content changed since last applied revision",
+			revision: "def",
+			status:   appsapi.HelmChartStatus{LastAppliedRevision: "abc"},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chartSourceUnchanged(tt.revision, tt.status); got != tt.want {
+				t.Errorf("chartSourceUnchanged(%q, %+v) = %v, want %v", tt.revision, tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeoutOrDefault(t *testing.T) {
+	if got := timeoutOrDefault(nil); got != defaultTimeout {
+		t.Errorf("timeoutOrDefault(nil) = %v, want %v", got, defaultTimeout)
+	}
+
+	custom := &metav1.Duration{Duration: 90 * time.Second}
+	if got := timeoutOrDefault(custom); got != custom.Duration {
+		t.Errorf("timeoutOrDefault(%v) = %v, want %v", custom, got, custom.Duration)
+	}
+}
+
+func TestMergeConditions(t *testing.T) {
+	existing := []appsapi.HelmChartCondition{
+		{Type: ConditionChartPulled, Status: corev1.ConditionTrue},
+		{Type: ConditionReady, Status: corev1.ConditionTrue},
+	}
+	updates := []appsapi.HelmChartCondition{
+		{Type: ConditionReady, Status: corev1.ConditionFalse, Reason: "BackOffLimitExceeded"},
+		{Type: ConditionReleased, Status: corev1.ConditionFalse},
+	}
+
+	merged := mergeConditions(existing, updates)
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 conditions after merge, got %d: %+v", len(merged), merged)
+	}
+
+	byType := make(map[string]appsapi.HelmChartCondition, len(merged))
+	for _, c := range merged {
+		byType[c.Type] = c
+	}
+
+	if byType[ConditionChartPulled].Status != corev1.ConditionTrue {
+		t.Errorf("ConditionChartPulled should be untouched by merge, got %+v", byType[ConditionChartPulled])
+	}
+	if byType[ConditionReady].Status != corev1.ConditionFalse || byType[ConditionReady].Reason != "BackOffLimitExceeded" {
+		t.Errorf("ConditionReady should be replaced in place by merge, got %+v", byType[ConditionReady])
+	}
+	if byType[ConditionReleased].Status != corev1.ConditionFalse {
+		t.Errorf("ConditionReleased should be appended by merge, got %+v", byType[ConditionReleased])
+	}
+}
+
+func TestFnv32Sharding(t *testing.T) {
+	const totalShards = 4
+	counts := make([]int, totalShards)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("ns/chart-%d", i)
+		counts[int(fnv32(key))%totalShards]++
+	}
+
+	for shard, count := range counts {
+		if count == 0 {
+			t.Errorf("shard %d received no keys out of 100, hashing looks skewed: %v", shard, counts)
+		}
+	}
+}